@@ -0,0 +1,212 @@
+// Package commit parses git commit messages according to the Conventional
+// Commits specification (https://www.conventionalcommits.org).
+package commit
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// LogFormat is the format string that should be passed to 'git log --format'
+// so that its output can be split into individual records and fed to Parse.
+// Each record is the commit hash followed by the raw, unwrapped commit
+// message, terminated by recordSep.
+const LogFormat = "%H%x00%B%x01"
+
+const (
+	recordSep = "\x01"
+	fieldSep  = "\x00"
+)
+
+// Footer is a single trailer on a commit, such as "Reviewed-by: Jane Doe" or
+// "BREAKING CHANGE: rewrites the public API".
+type Footer struct {
+	Token string
+	Value string
+}
+
+// Commit is a single commit message decomposed into its Conventional Commits
+// parts.
+type Commit struct {
+	Hash     string
+	Type     string
+	Scope    string
+	Subject  string
+	Body     string
+	Footers  []Footer
+	Breaking bool
+}
+
+// BreakingChange returns the body of the "BREAKING CHANGE:" (or
+// "BREAKING-CHANGE:") footer, if one is present.
+func (c Commit) BreakingChange() string {
+	for _, f := range c.Footers {
+		if f.Token == "BREAKING CHANGE" || f.Token == "BREAKING-CHANGE" {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+var headerRe = regexp.MustCompile(`^(\w+)(\(([^)]+)\))?(!)?: ?(.*)$`)
+
+// footerRe matches a single footer line, either "Token: value" or the git
+// trailer convention of "Token #value".
+var footerRe = regexp.MustCompile(`^([A-Za-z][A-Za-z-]*|BREAKING CHANGE)(: | #)(.*)$`)
+
+var errNotConventional = errors.New("not a conventional commit")
+
+// Parser parses raw git log output into a slice of Commit.
+type Parser struct{}
+
+// NewParser returns a Parser ready for use.
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// Parse splits raw (the output of 'git log' formatted with LogFormat) into
+// individual commits, and parses each one according to the Conventional
+// Commits spec. Commits whose header does not match the spec are skipped,
+// since not every commit in a history is expected to be conventional.
+func (p *Parser) Parse(raw string) ([]Commit, error) {
+	var commits []Commit
+
+	for _, rec := range strings.Split(raw, recordSep) {
+		rec = strings.TrimPrefix(rec, "\n")
+		rec = strings.TrimSpace(rec)
+
+		if rec == "" {
+			continue
+		}
+
+		parts := strings.SplitN(rec, fieldSep, 2)
+
+		if len(parts) != 2 {
+			continue
+		}
+
+		c, err := p.parseMessage(strings.TrimSpace(parts[1]))
+
+		if err != nil {
+			continue
+		}
+		c.Hash = parts[0]
+
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+// parseMessage parses a single, full commit message into a Commit.
+func (p *Parser) parseMessage(msg string) (Commit, error) {
+	var c Commit
+
+	lines := strings.Split(msg, "\n")
+
+	m := headerRe.FindStringSubmatch(lines[0])
+
+	if m == nil {
+		return c, errNotConventional
+	}
+
+	c.Type = m[1]
+	c.Scope = m[3]
+	c.Subject = m[5]
+	c.Breaking = m[4] == "!"
+
+	rest := lines[1:]
+
+	for len(rest) > 0 && rest[0] == "" {
+		rest = rest[1:]
+	}
+
+	paragraphs := splitParagraphs(rest)
+
+	bodyParas := paragraphs
+
+	if n := len(paragraphs); n > 0 && isFooterParagraph(paragraphs[n-1]) {
+		footer := paragraphs[n-1]
+		bodyParas = paragraphs[:n-1]
+
+		for _, line := range footer {
+			fm := footerRe.FindStringSubmatch(line)
+
+			if fm == nil {
+				// A continuation line (indented) belongs to the footer
+				// above it, unless it opens the paragraph with nothing
+				// to attach to, which isFooterParagraph doesn't rule out.
+				if n := len(c.Footers); n > 0 {
+					c.Footers[n-1].Value += "\n" + strings.TrimSpace(line)
+				}
+				continue
+			}
+
+			token := fm[1]
+			value := fm[3]
+
+			if token == "BREAKING CHANGE" || token == "BREAKING-CHANGE" {
+				c.Breaking = true
+			}
+
+			c.Footers = append(c.Footers, Footer{Token: token, Value: value})
+		}
+	}
+
+	bodyLines := make([]string, 0, len(rest))
+
+	for _, para := range bodyParas {
+		if len(bodyLines) > 0 {
+			bodyLines = append(bodyLines, "")
+		}
+		bodyLines = append(bodyLines, para...)
+	}
+
+	c.Body = strings.TrimSpace(strings.Join(bodyLines, "\n"))
+
+	return c, nil
+}
+
+// splitParagraphs groups lines into paragraphs, separated by one or more
+// blank lines.
+func splitParagraphs(lines []string) [][]string {
+	var paras [][]string
+	var cur []string
+
+	for _, line := range lines {
+		if line == "" {
+			if len(cur) > 0 {
+				paras = append(paras, cur)
+				cur = nil
+			}
+			continue
+		}
+		cur = append(cur, line)
+	}
+
+	if len(cur) > 0 {
+		paras = append(paras, cur)
+	}
+	return paras
+}
+
+// isFooterParagraph reports whether every line in para looks like a git
+// trailer, as required for the final paragraph of a commit message to be
+// treated as the footers block.
+func isFooterParagraph(para []string) bool {
+	if len(para) == 0 {
+		return false
+	}
+
+	for _, line := range para {
+		if footerRe.MatchString(line) {
+			continue
+		}
+		// A continuation line (indented) belongs to the footer above it.
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
+			continue
+		}
+		return false
+	}
+	return true
+}
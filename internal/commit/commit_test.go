@@ -0,0 +1,154 @@
+package commit
+
+import "testing"
+
+// record builds a single LogFormat-style record (hash\x00message\x01),
+// the same shape 'git log --format=LogFormat' produces.
+func record(hash, message string) string {
+	return hash + fieldSep + message + recordSep
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want Commit
+	}{
+		{
+			name: "simple fix",
+			msg:  "fix: correct off-by-one",
+			want: Commit{Hash: "abc1", Type: "fix", Subject: "correct off-by-one"},
+		},
+		{
+			name: "scoped feat",
+			msg:  "feat(api): add pagination",
+			want: Commit{Hash: "abc1", Type: "feat", Scope: "api", Subject: "add pagination"},
+		},
+		{
+			name: "bang marks breaking",
+			msg:  "feat!: drop legacy endpoint",
+			want: Commit{Hash: "abc1", Type: "feat", Subject: "drop legacy endpoint", Breaking: true},
+		},
+		{
+			name: "non-final footer-shaped paragraph stays in the body",
+			msg: "fix: correct retry logic\n\n" +
+				"This is the body of the commit.\n\n" +
+				"Note: this requires a migration.\n\n" +
+				"See the migration guide for details.",
+			want: Commit{
+				Hash:    "abc1",
+				Type:    "fix",
+				Subject: "correct retry logic",
+				Body: "This is the body of the commit.\n\n" +
+					"Note: this requires a migration.\n\n" +
+					"See the migration guide for details.",
+			},
+		},
+		{
+			name: "body with trailing footer",
+			msg: "fix: correct retry logic\n\n" +
+				"This is the body of the commit.\n\n" +
+				"Note: this requires a migration.\n\n" +
+				"BREAKING CHANGE: retries are no longer silent\n" +
+				"Reviewed-by: Jane Doe",
+			want: Commit{
+				Hash:     "abc1",
+				Type:     "fix",
+				Subject:  "correct retry logic",
+				Body:     "This is the body of the commit.\n\nNote: this requires a migration.",
+				Breaking: true,
+				Footers: []Footer{
+					{Token: "BREAKING CHANGE", Value: "retries are no longer silent"},
+					{Token: "Reviewed-by", Value: "Jane Doe"},
+				},
+			},
+		},
+		{
+			name: "git trailer hash convention",
+			msg:  "fix: patch CVE\n\nRefs #123",
+			want: Commit{
+				Hash:    "abc1",
+				Type:    "fix",
+				Subject: "patch CVE",
+				Footers: []Footer{{Token: "Refs", Value: "123"}},
+			},
+		},
+		{
+			name: "wrapped footer line continues the footer above it",
+			msg: "fix: correct retry logic\n\n" +
+				"BREAKING CHANGE: retries are no longer silent and now\n" +
+				"  surface the underlying transport error to the caller\n" +
+				"Reviewed-by: Jane Doe",
+			want: Commit{
+				Hash:     "abc1",
+				Type:     "fix",
+				Subject:  "correct retry logic",
+				Breaking: true,
+				Footers: []Footer{
+					{
+						Token: "BREAKING CHANGE",
+						Value: "retries are no longer silent and now\nsurface the underlying transport error to the caller",
+					},
+					{Token: "Reviewed-by", Value: "Jane Doe"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits, err := NewParser().Parse(record("abc1", tt.msg))
+
+			if err != nil {
+				t.Fatalf("Parse: unexpected error: %s", err)
+			}
+			if len(commits) != 1 {
+				t.Fatalf("Parse: got %d commits, want 1", len(commits))
+			}
+
+			got := commits[0]
+
+			if got.Hash != tt.want.Hash || got.Type != tt.want.Type || got.Scope != tt.want.Scope ||
+				got.Subject != tt.want.Subject || got.Body != tt.want.Body || got.Breaking != tt.want.Breaking {
+				t.Fatalf("Parse = %+v, want %+v", got, tt.want)
+			}
+
+			if len(got.Footers) != len(tt.want.Footers) {
+				t.Fatalf("Parse: got %d footers %+v, want %d %+v", len(got.Footers), got.Footers, len(tt.want.Footers), tt.want.Footers)
+			}
+			for i, f := range tt.want.Footers {
+				if got.Footers[i] != f {
+					t.Fatalf("Parse: footer %d = %+v, want %+v", i, got.Footers[i], f)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSkipsNonConventionalCommits(t *testing.T) {
+	raw := record("a1", "Merge branch 'main' into feature") + record("a2", "fix: real change")
+
+	commits, err := NewParser().Parse(raw)
+
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %s", err)
+	}
+	if len(commits) != 1 {
+		t.Fatalf("Parse: got %d commits, want 1", len(commits))
+	}
+	if commits[0].Hash != "a2" {
+		t.Fatalf("Parse: got hash %q, want %q", commits[0].Hash, "a2")
+	}
+}
+
+func TestBreakingChange(t *testing.T) {
+	c := Commit{Footers: []Footer{{Token: "BREAKING CHANGE", Value: "rewrites the public API"}}}
+
+	if got := c.BreakingChange(); got != "rewrites the public API" {
+		t.Fatalf("BreakingChange() = %q, want %q", got, "rewrites the public API")
+	}
+
+	if got := (Commit{}).BreakingChange(); got != "" {
+		t.Fatalf("BreakingChange() = %q, want empty", got)
+	}
+}
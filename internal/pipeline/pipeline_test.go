@@ -0,0 +1,181 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// withGitDir chdirs into a fresh temp directory with a .git directory, so
+// Run has somewhere to write StatePath, and restores the original working
+// directory once the test finishes.
+func withGitDir(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		os.Chdir(wd)
+	})
+}
+
+// steps returns a Pipeline of n no-op steps, each appending its own name to
+// order as it executes, so a test can assert which steps actually ran.
+func steps(n int, order *[]string) *Pipeline {
+	s := make([]Step, n)
+
+	for i := range s {
+		name := string(rune('a' + i))
+		s[i] = Step{
+			Name: name,
+			Run: func(*State) error {
+				*order = append(*order, name)
+				return nil
+			},
+		}
+	}
+	return New(s...)
+}
+
+func TestRunDefaultsToEveryStep(t *testing.T) {
+	withGitDir(t)
+
+	var order []string
+
+	if err := steps(3, &order).Run(&State{}, 0, 0); err != nil {
+		t.Fatalf("Run: %s", err)
+	}
+	if got := len(order); got != 3 {
+		t.Fatalf("ran %d steps, want 3", got)
+	}
+}
+
+func TestRunStepRange(t *testing.T) {
+	withGitDir(t)
+
+	tests := []struct {
+		name      string
+		from, to  int
+		wantOrder []string
+		wantErr   bool
+	}{
+		{name: "middle step only", from: 2, to: 2, wantOrder: []string{"b"}},
+		{name: "resume from middle to end", from: 2, to: 3, wantOrder: []string{"b", "c"}},
+		{name: "from defaults to first", from: 0, to: 2, wantOrder: []string{"a", "b"}},
+		{name: "to defaults to last", from: 2, to: 0, wantOrder: []string{"b", "c"}},
+		{name: "from past to is out of bounds", from: 3, to: 1, wantErr: true},
+		{name: "to past the step count is out of bounds", from: 1, to: 4, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var order []string
+
+			err := steps(3, &order).Run(&State{}, tt.from, tt.to)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Run(from=%d, to=%d) = nil, want error", tt.from, tt.to)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Run(from=%d, to=%d): %s", tt.from, tt.to, err)
+			}
+
+			if len(order) != len(tt.wantOrder) {
+				t.Fatalf("ran %v, want %v", order, tt.wantOrder)
+			}
+			for i, name := range tt.wantOrder {
+				if order[i] != name {
+					t.Fatalf("ran %v, want %v", order, tt.wantOrder)
+				}
+			}
+		})
+	}
+}
+
+func TestRunResumesWithStateFromEarlierSteps(t *testing.T) {
+	withGitDir(t)
+
+	p := New(
+		Step{
+			Name: "first",
+			Run: func(s *State) error {
+				s.Version = "v1.0.0"
+				return nil
+			},
+		},
+		Step{
+			Name: "second",
+			Run: func(s *State) error {
+				s.Tag = "v" + s.Version
+				return nil
+			},
+		},
+	)
+
+	state := &State{}
+
+	if err := p.Run(state, 1, 1); err != nil {
+		t.Fatalf("Run(1, 1): %s", err)
+	}
+	if state.Tag != "" {
+		t.Fatalf("Tag = %q after only the first step ran, want empty", state.Tag)
+	}
+
+	// A later, separate invocation resumes against the State the first
+	// one left behind, the way a retried or CI-split release would.
+	if err := p.Run(state, 2, 2); err != nil {
+		t.Fatalf("Run(2, 2): %s", err)
+	}
+	if state.Tag != "vv1.0.0" {
+		t.Fatalf("Tag = %q, want %q", state.Tag, "vv1.0.0")
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	path := t.TempDir() + "/release-state.json"
+
+	want := State{Version: "v1.2.3", Tag: "v1.2.3", Commits: nil}
+
+	if err := Save(path, want); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+
+	got, err := Load(path)
+
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Load = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadMissingFileReturnsZeroState(t *testing.T) {
+	state, err := Load(t.TempDir() + "/does-not-exist.json")
+
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	if !reflect.DeepEqual(state, State{}) {
+		t.Fatalf("Load of a missing file = %+v, want zero State", state)
+	}
+}
@@ -0,0 +1,156 @@
+// Package repo provides the git operations release needs, behind an
+// interface so a fake can stand in for it in tests. It shells out to the
+// git binary rather than depending on a pure-Go implementation: pulling
+// one in added a transitive dependency graph (go-billy, gcfg, ssh_config,
+// x/crypto and friends) that this module's build environments cannot
+// always reach, and a tool that can't reliably fetch its own dependencies
+// is worse than one that requires git on PATH.
+package repo
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoTags is returned by Describe when no tag matches pattern and is
+// reachable from HEAD.
+var ErrNoTags = errors.New("repo: no tags found")
+
+// Repo is the set of git operations release performs, abstracted so a
+// fake can stand in for it in tests.
+type Repo interface {
+	// Describe returns the name of the most recently created tag
+	// reachable from HEAD whose name matches pattern (a filepath.Match
+	// glob), equivalent to 'git describe --abbrev=0 --match pattern'.
+	Describe(pattern string) (string, error)
+
+	// Log formats every commit in revRange (single revision or "A..B"
+	// range) according to format, one after another with no separator
+	// of its own; format is expected to embed one, as commit.LogFormat
+	// does. If paths is non-empty, commits that touch none of them are
+	// excluded, equivalent to 'git log revRange --format=format -- paths'.
+	Log(revRange string, paths []string, format string) (string, error)
+
+	// Tag creates an annotated tag named name, pointing at HEAD, with
+	// the given message.
+	Tag(name, message string) error
+
+	// Head returns the full hash of the commit HEAD points at.
+	Head() (string, error)
+
+	// Archive writes a gzipped tarball of rev's tree to path.
+	Archive(rev, path string) error
+}
+
+type gitRepo struct {
+	dir string
+}
+
+// Open opens the git repository at path.
+func Open(path string) (Repo, error) {
+	r := &gitRepo{dir: path}
+
+	if _, err := r.git("rev-parse", "--git-dir"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// git runs git with a, rooted at r.dir, and returns its stdout. On
+// failure it returns the first line of stderr, since git puts its
+// actual complaint there and the rest is usually usage noise.
+func (r *gitRepo) git(a ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", a...)
+	cmd.Dir = r.dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		lines := strings.Split(stderr.String(), "\n")
+		return "", errors.New(lines[0])
+	}
+	return stdout.String(), nil
+}
+
+// noTagsPhrases are the distinct ways 'git describe' phrases "there's
+// nothing here to describe", across no tags existing at all, none
+// matching --match, and none reachable from HEAD. Any other failure (a
+// shallow clone, a detached HEAD with a broken ref, not being a git
+// repository at all, ...) is a real error and must not be mistaken for
+// ErrNoTags.
+var noTagsPhrases = []string{
+	"no names found",
+	"no tags can describe",
+	"no annotated tags can describe",
+}
+
+func (r *gitRepo) Describe(pattern string) (string, error) {
+	a := []string{"describe", "--abbrev=0"}
+
+	if pattern != "" {
+		a = append(a, "--match", pattern)
+	}
+
+	out, err := r.git(a...)
+
+	if err != nil {
+		return "", classifyDescribeErr(err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// classifyDescribeErr turns the error from a failed 'git describe' into
+// ErrNoTags when its message says there was nothing to describe, and
+// passes through any other error (a shallow clone, a broken ref, not
+// being a git repository at all, ...) unchanged.
+func classifyDescribeErr(err error) error {
+	msg := strings.ToLower(err.Error())
+
+	for _, phrase := range noTagsPhrases {
+		if strings.Contains(msg, phrase) {
+			return ErrNoTags
+		}
+	}
+	return err
+}
+
+func (r *gitRepo) Log(revRange string, paths []string, format string) (string, error) {
+	a := []string{"log", revRange, "--format=" + format}
+
+	if len(paths) > 0 {
+		a = append(a, "--")
+		a = append(a, paths...)
+	}
+
+	return r.git(a...)
+}
+
+func (r *gitRepo) Tag(name, message string) error {
+	_, err := r.git("tag", "-a", name, "-m", message)
+	return err
+}
+
+func (r *gitRepo) Head() (string, error) {
+	out, err := r.git("rev-parse", "HEAD")
+
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (r *gitRepo) Archive(rev, path string) error {
+	abs, err := filepath.Abs(path)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = r.git("archive", "--format=tar.gz", "-o", abs, rev)
+	return err
+}
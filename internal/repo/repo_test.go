@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassifyDescribeErr(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+	}{
+		{name: "no tags at all", msg: "fatal: No names found, cannot describe anything."},
+		{name: "no tag matches --match", msg: "fatal: No tags can describe '18aa655'."},
+		{name: "only unannotated tags", msg: "fatal: No annotated tags can describe '18aa655'."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyDescribeErr(errors.New(tt.msg))
+
+			if !errors.Is(got, ErrNoTags) {
+				t.Fatalf("classifyDescribeErr(%q) = %v, want ErrNoTags", tt.msg, got)
+			}
+		})
+	}
+
+	t.Run("unrelated git failure passes through", func(t *testing.T) {
+		in := errors.New("fatal: Not a valid object name HEAD")
+
+		got := classifyDescribeErr(in)
+
+		if got != in {
+			t.Fatalf("classifyDescribeErr(%q) = %v, want the original error unchanged", in, got)
+		}
+	})
+}
+
+// initRepo creates a fresh git repository in a temp directory with a
+// single commit, and returns the directory it lives in.
+func initRepo(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	run := func(a ...string) {
+		cmd := exec.Command("git", a...)
+		cmd.Dir = dir
+
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s: %s", a, err, out)
+		}
+	}
+
+	run("init", "-q")
+	run("config", "user.email", "repo-test@example.com")
+	run("config", "user.name", "repo-test")
+	run("commit", "-q", "--allow-empty", "-m", "init")
+
+	return dir
+}
+
+func TestDescribeNoTagsYet(t *testing.T) {
+	r, err := Open(initRepo(t))
+
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+
+	if _, err := r.Describe(""); !errors.Is(err, ErrNoTags) {
+		t.Fatalf("Describe on a tagless repo = %v, want ErrNoTags", err)
+	}
+}
+
+func TestDescribeReturnsLatestTag(t *testing.T) {
+	dir := initRepo(t)
+
+	cmd := exec.Command("git", "tag", "-a", "v1.2.3", "-m", "v1.2.3")
+	cmd.Dir = dir
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git tag: %s: %s", err, out)
+	}
+
+	r, err := Open(dir)
+
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+
+	got, err := r.Describe("")
+
+	if err != nil {
+		t.Fatalf("Describe: %s", err)
+	}
+	if got != "v1.2.3" {
+		t.Fatalf("Describe = %q, want %q", got, "v1.2.3")
+	}
+}
+
+func TestOpenRejectsNonGitDirectory(t *testing.T) {
+	if _, err := Open(t.TempDir()); err == nil {
+		t.Fatal("Open on a non-git directory = nil error, want error")
+	}
+}
+
+func TestOpenRejectsMissingDirectory(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "does-not-exist")); err == nil {
+		t.Fatal("Open on a missing directory = nil error, want error")
+	}
+}
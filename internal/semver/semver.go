@@ -0,0 +1,205 @@
+// Package semver implements parsing, bumping and comparison of versions
+// against the SemVer 2.0.0 grammar (https://semver.org).
+package semver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalid is returned by Parse when its input does not conform to the
+// SemVer 2.0.0 grammar.
+var ErrInvalid = errors.New("invalid semver")
+
+// Bump is the part of a Semver to increment.
+type Bump int
+
+const (
+	Patch Bump = iota + 1
+	Minor
+	Major
+)
+
+// semverRe is the official SemVer 2.0.0 grammar, see
+// https://semver.org/#is-there-a-suggested-regular-expression-regex-to-check-a-semver-string.
+var semverRe = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// Semver is a parsed SemVer 2.0.0 version.
+type Semver struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
+}
+
+// Parse parses s, which may carry a leading "v", against the SemVer 2.0.0
+// grammar.
+func Parse(s string) (Semver, error) {
+	var sem Semver
+
+	s = strings.TrimPrefix(s, "v")
+
+	m := semverRe.FindStringSubmatch(s)
+
+	if m == nil {
+		return sem, ErrInvalid
+	}
+
+	major, err := strconv.Atoi(m[1])
+
+	if err != nil {
+		return sem, err
+	}
+
+	minor, err := strconv.Atoi(m[2])
+
+	if err != nil {
+		return sem, err
+	}
+
+	patch, err := strconv.Atoi(m[3])
+
+	if err != nil {
+		return sem, err
+	}
+
+	sem.Major = major
+	sem.Minor = minor
+	sem.Patch = patch
+	sem.Prerelease = m[4]
+	sem.Build = m[5]
+
+	return sem, nil
+}
+
+// Bump increments the given part of sem, resetting everything below it and
+// clearing the prerelease and build metadata, per the usual release
+// convention.
+func (sem *Semver) Bump(b Bump) {
+	sem.Prerelease = ""
+	sem.Build = ""
+
+	switch b {
+	case Patch:
+		sem.Patch++
+	case Minor:
+		sem.Patch = 0
+		sem.Minor++
+	case Major:
+		sem.Patch = 0
+		sem.Minor = 0
+		sem.Major++
+	}
+}
+
+// BumpPrerelease advances the prerelease identifier. If the current
+// prerelease already carries label as its leading identifier followed by a
+// numeric identifier (e.g. "rc.1"), that numeric identifier is
+// incremented ("rc.2"). Otherwise the prerelease is reset to "<label>.1".
+func (sem *Semver) BumpPrerelease(label string) {
+	parts := strings.SplitN(sem.Prerelease, ".", 2)
+
+	if len(parts) == 2 && parts[0] == label {
+		if n, err := strconv.Atoi(parts[1]); err == nil {
+			sem.Prerelease = fmt.Sprintf("%s.%d", label, n+1)
+			return
+		}
+	}
+
+	sem.Prerelease = label + ".1"
+}
+
+// String formats sem as "vMAJOR.MINOR.PATCH[-PRERELEASE][+BUILD]".
+func (sem Semver) String() string {
+	s := fmt.Sprintf("v%d.%d.%d", sem.Major, sem.Minor, sem.Patch)
+
+	if sem.Prerelease != "" {
+		s += "-" + sem.Prerelease
+	}
+	if sem.Build != "" {
+		s += "+" + sem.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 as a is less than, equal to, or greater than
+// b in precedence, following the SemVer 2.0.0 rules. Build metadata is
+// ignored, as the spec requires.
+func Compare(a, b Semver) int {
+	if a.Major != b.Major {
+		return cmpInt(a.Major, b.Major)
+	}
+	if a.Minor != b.Minor {
+		return cmpInt(a.Minor, b.Minor)
+	}
+	if a.Patch != b.Patch {
+		return cmpInt(a.Patch, b.Patch)
+	}
+	return comparePrerelease(a.Prerelease, b.Prerelease)
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePrerelease compares two dot-separated prerelease strings.  A
+// version without a prerelease has higher precedence than one with, per
+// the spec.
+func comparePrerelease(a, b string) int {
+	if a == "" && b == "" {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareIdentifier(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return cmpInt(len(as), len(bs))
+}
+
+// compareIdentifier compares a single dot-separated prerelease identifier.
+// Numeric identifiers are compared numerically and always have lower
+// precedence than alphanumeric ones, which are compared lexically.
+func compareIdentifier(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+
+	// Atoi accepts a leading "-", but an identifier such as "-1" is
+	// alphanumeric in SemVer, not numeric.
+	aNum := aErr == nil && a[0] != '-'
+	bNum := bErr == nil && b[0] != '-'
+
+	if aNum && bNum {
+		return cmpInt(an, bn)
+	}
+	if aNum != bNum {
+		if aNum {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,139 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Semver
+		wantErr bool
+	}{
+		{in: "v1.2.3", want: Semver{Major: 1, Minor: 2, Patch: 3}},
+		{in: "1.2.3", want: Semver{Major: 1, Minor: 2, Patch: 3}},
+		{in: "v0.0.0", want: Semver{}},
+		{in: "v1.2.3-rc.1", want: Semver{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1"}},
+		{in: "v1.2.3-rc.1+abcdef0", want: Semver{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1", Build: "abcdef0"}},
+		{in: "v1.2.3+abcdef0", want: Semver{Major: 1, Minor: 2, Patch: 3, Build: "abcdef0"}},
+		{in: "", wantErr: true},
+		{in: "v1.2", wantErr: true},
+		{in: "v1.2.3-", wantErr: true},
+		{in: "v01.2.3", wantErr: true},
+		{in: "vx.y.z", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q) = %+v, nil; want error", tt.in, got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %s", tt.in, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBump(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Semver
+		b    Bump
+		want Semver
+	}{
+		{name: "patch", in: Semver{Major: 1, Minor: 2, Patch: 3}, b: Patch, want: Semver{Major: 1, Minor: 2, Patch: 4}},
+		{name: "minor resets patch", in: Semver{Major: 1, Minor: 2, Patch: 3}, b: Minor, want: Semver{Major: 1, Minor: 3, Patch: 0}},
+		{name: "major resets minor and patch", in: Semver{Major: 1, Minor: 2, Patch: 3}, b: Major, want: Semver{Major: 2, Minor: 0, Patch: 0}},
+		{
+			name: "clears prerelease and build",
+			in:   Semver{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1", Build: "abcdef0"},
+			b:    Patch,
+			want: Semver{Major: 1, Minor: 2, Patch: 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sem := tt.in
+			sem.Bump(tt.b)
+
+			if sem != tt.want {
+				t.Fatalf("Bump(%v) = %+v, want %+v", tt.b, sem, tt.want)
+			}
+		})
+	}
+}
+
+func TestBumpPrerelease(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    Semver
+		label string
+		want  string
+	}{
+		{name: "no prerelease yet", in: Semver{}, label: "rc", want: "rc.1"},
+		{name: "same label increments", in: Semver{Prerelease: "rc.1"}, label: "rc", want: "rc.2"},
+		{name: "same label double digit", in: Semver{Prerelease: "rc.9"}, label: "rc", want: "rc.10"},
+		{name: "different label resets", in: Semver{Prerelease: "beta.3"}, label: "rc", want: "rc.1"},
+		{name: "non-numeric trailing identifier resets", in: Semver{Prerelease: "rc.x"}, label: "rc", want: "rc.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sem := tt.in
+			sem.BumpPrerelease(tt.label)
+
+			if sem.Prerelease != tt.want {
+				t.Fatalf("BumpPrerelease(%q) = %q, want %q", tt.label, sem.Prerelease, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "v1.2.3", b: "v1.2.3", want: 0},
+		{a: "v1.2.4", b: "v1.2.3", want: 1},
+		{a: "v1.2.3", b: "v1.2.4", want: -1},
+		{a: "v2.0.0", b: "v1.9.9", want: 1},
+		{a: "v1.2.3", b: "v1.2.3-rc.1", want: 1},
+		{a: "v1.2.3-rc.1", b: "v1.2.3", want: -1},
+		{a: "v1.2.3-rc.1", b: "v1.2.3-rc.2", want: -1},
+		{a: "v1.2.3-rc.2", b: "v1.2.3-rc.1", want: 1},
+		{a: "v1.2.3-alpha", b: "v1.2.3-beta", want: -1},
+		{a: "v1.2.3-1", b: "v1.2.3-alpha", want: -1},
+		{a: "v1.2.3-rc.1", b: "v1.2.3-rc.1.1", want: -1},
+		{a: "v1.2.3+build1", b: "v1.2.3+build2", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"_vs_"+tt.b, func(t *testing.T) {
+			a, err := Parse(tt.a)
+
+			if err != nil {
+				t.Fatalf("Parse(%q): %s", tt.a, err)
+			}
+
+			b, err := Parse(tt.b)
+
+			if err != nil {
+				t.Fatalf("Parse(%q): %s", tt.b, err)
+			}
+
+			if got := Compare(a, b); got != tt.want {
+				t.Fatalf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}